@@ -0,0 +1,279 @@
+package aghnet
+
+import (
+	"net"
+	"sort"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// errNoSourceAddr is returned by SelectSourceAddr when the host has no
+// address of the destination's family to offer as a source.
+const errNoSourceAddr errors.Error = "no candidate source address found"
+
+// addrScope is the RFC 4007/6724 scope of an address: smaller values are
+// narrower in reach.
+type addrScope int
+
+// Address scopes, ordered from narrowest to widest, as used by the RFC 6724
+// "prefer matching scope" rule.
+const (
+	scopeInterfaceLocal addrScope = iota
+	scopeLinkLocal
+	scopeAdminLocal
+	scopeSiteLocal
+	scopeOrgLocal
+	scopeGlobal
+)
+
+// candidateAddr is a source-address candidate together with the RFC 6724
+// attributes needed to rank it against the others.
+type candidateAddr struct {
+	ip net.IP
+
+	iface      string
+	ifaceIndex int
+
+	scope addrScope
+
+	// deprecated is true for IPv6 addresses in the "deprecated" preferred
+	// lifetime state (RFC 4862).
+	deprecated bool
+
+	// careOf is true for IPv6 "care-of" addresses, as opposed to "home"
+	// addresses (RFC 6275).  Plain, non-mobile hosts never set this.
+	careOf bool
+}
+
+// addrCandidatesFunc enumerates every unicast address bound to the host,
+// along with its interface and RFC 6724 attributes (deprecated, care-of).
+// It's a variable so that tests can swap it out with a synthetic interface
+// table, the same way aghosRunCommand is swappable.  enumerateAddrCandidates
+// itself is platform-specific; see addrselect_linux.go and
+// addrselect_others.go.
+var addrCandidatesFunc = enumerateAddrCandidates
+
+// outgoingInterfaceFunc reports the interface the kernel would route dst
+// out of, used by the RFC 6724 "prefer outgoing interface" rule.  It's a
+// variable for the same reason addrCandidatesFunc is.  outgoingInterface is
+// platform-specific.
+var outgoingInterfaceFunc = outgoingInterface
+
+// classifyScope returns the RFC 4007/6724 scope of ip.
+func classifyScope(ip net.IP) addrScope {
+	if ip.IsLoopback() || ip.IsInterfaceLocalMulticast() {
+		return scopeInterfaceLocal
+	}
+
+	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return scopeLinkLocal
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		// RFC 1918 private ranges are given site scope, matching the
+		// treatment of the deprecated IPv6 site-local range.
+		if ip4[0] == 10 ||
+			(ip4[0] == 172 && ip4[1]&0xf0 == 16) ||
+			(ip4[0] == 192 && ip4[1] == 168) {
+			return scopeSiteLocal
+		}
+
+		return scopeGlobal
+	}
+
+	if ip[0] == 0xfe && ip[1]&0xc0 == 0xc0 {
+		// fec0::/10, deprecated IPv6 site-local.
+		return scopeSiteLocal
+	}
+
+	if ip[0] == 0xfc || ip[0] == 0xfd {
+		// fc00::/7, unique local addresses; treated as site-local for
+		// scope-matching purposes.
+		return scopeSiteLocal
+	}
+
+	return scopeGlobal
+}
+
+// policyEntry is a row of the RFC 6724 Table 2 policy table used for the
+// "prefer matching label" rule.
+type policyEntry struct {
+	prefix *net.IPNet
+	label  int
+}
+
+// policyTable is RFC 6724's Table 2, used to compute each address's label
+// for the "prefer matching label" rule.
+var policyTable = buildPolicyTable()
+
+func buildPolicyTable() []policyEntry {
+	mustParse := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+
+		return n
+	}
+
+	return []policyEntry{
+		{prefix: mustParse("::1/128"), label: 0},
+		{prefix: mustParse("::/0"), label: 1},
+		{prefix: mustParse("2002::/16"), label: 2},
+		{prefix: mustParse("::/96"), label: 3},
+		{prefix: mustParse("::ffff:0:0/96"), label: 4},
+		{prefix: mustParse("fec0::/10"), label: 5},
+		{prefix: mustParse("2001::/32"), label: 5},
+		{prefix: mustParse("fc00::/7"), label: 13},
+	}
+}
+
+// classifyLabel returns ip's RFC 6724 label, used for the "prefer matching
+// label" rule.  IPv4 addresses are mapped into ::ffff:0:0/96 first, just
+// like the table entry for that prefix implies.
+func classifyLabel(ip net.IP) int {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip.To16()
+	}
+
+	longest := -1
+	label := 1
+	for _, p := range policyTable {
+		if !p.prefix.Contains(ip) {
+			continue
+		}
+
+		ones, _ := p.prefix.Mask.Size()
+		if ones > longest {
+			longest = ones
+			label = p.label
+		}
+	}
+
+	return label
+}
+
+// commonPrefixLen returns the number of leading bits that a and b, both
+// 16-byte addresses, have in common.
+func commonPrefixLen(a, b net.IP) (n int) {
+	a, b = a.To16(), b.To16()
+	if a == nil || b == nil {
+		return 0
+	}
+
+	for i := 0; i < net.IPv6len; i++ {
+		x := a[i] ^ b[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+
+		break
+	}
+
+	return n
+}
+
+// SelectSourceAddr implements the RFC 6724 destination/source-address
+// selection rules (the same ones Go's net package applies internally to
+// order DNS results) to pick the best local address and interface to use
+// when reaching dst.
+//
+// This repo snapshot has no DNS upstream package to call it from, so it
+// isn't wired into one yet; callers that need outbound-source selection
+// for upstream DNS connections (or anything else) can call it directly.
+func SelectSourceAddr(dst net.IP) (src net.IP, iface string, err error) {
+	cands, err := addrCandidatesFunc()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var matching []candidateAddr
+	wantV4 := dst.To4() != nil
+	for _, c := range cands {
+		if (c.ip.To4() != nil) == wantV4 {
+			matching = append(matching, c)
+		}
+	}
+
+	if len(matching) == 0 {
+		return nil, "", errNoSourceAddr
+	}
+
+	dstScope := classifyScope(dst)
+	dstLabel := classifyLabel(dst)
+	outIface, haveOutIface := outgoingInterfaceFunc(dst)
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return less(dst, dstScope, dstLabel, outIface, haveOutIface, matching[i], matching[j])
+	})
+
+	best := matching[0]
+
+	return best.ip, best.iface, nil
+}
+
+// less reports whether a should be preferred over b as dst's source
+// address, applying the RFC 6724 rules in order.  outIface and
+// haveOutIface are the result of outgoingInterfaceFunc(dst), used by
+// Rule 5.
+func less(
+	dst net.IP,
+	dstScope addrScope,
+	dstLabel int,
+	outIface string,
+	haveOutIface bool,
+	a, b candidateAddr,
+) bool {
+	// Rule 1: prefer same address.
+	if aSame, bSame := a.ip.Equal(dst), b.ip.Equal(dst); aSame != bSame {
+		return aSame
+	}
+
+	// Rule 2: prefer appropriate scope (smallest scope that's >= dstScope,
+	// otherwise the largest available).
+	if a.scope != b.scope {
+		if a.scope < dstScope && b.scope < dstScope {
+			return a.scope > b.scope
+		}
+
+		if (a.scope >= dstScope) != (b.scope >= dstScope) {
+			return a.scope >= dstScope
+		}
+
+		return a.scope < b.scope
+	}
+
+	// Rule 3: avoid deprecated addresses.
+	if a.deprecated != b.deprecated {
+		return !a.deprecated
+	}
+
+	// Rule 4: prefer home addresses over care-of addresses.
+	if a.careOf != b.careOf {
+		return !a.careOf
+	}
+
+	// Rule 5: prefer the outgoing interface, i.e. an address whose
+	// interface is the one the kernel would actually route dst out of.
+	if haveOutIface {
+		if aOut, bOut := a.iface == outIface, b.iface == outIface; aOut != bOut {
+			return aOut
+		}
+	}
+
+	// Rule 6: prefer matching label.
+	if aMatch, bMatch := classifyLabel(a.ip) == dstLabel, classifyLabel(b.ip) == dstLabel; aMatch != bMatch {
+		return aMatch
+	}
+
+	// Rule 8 (longest matching prefix) is the final tiebreaker we
+	// implement; the rules about multicast scope and native transport
+	// (7, 7.5) don't apply to locally-bound unicast addresses.
+	return commonPrefixLen(a.ip, dst) > commonPrefixLen(b.ip, dst)
+}
@@ -0,0 +1,218 @@
+//go:build linux
+
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// addrselectIfAddrMsgLen is the size, in bytes, of the fixed part of a
+// struct ifaddrmsg; see rtnetlink(7).  It has the same value as ifAddrMsgLen
+// in watch_linux.go.
+const addrselectIfAddrMsgLen = 8
+
+// enumerateAddrCandidates lists the unicast addresses of every interface on
+// the host via an RTM_GETADDR dump, reading each address's IFA_FLAGS to
+// populate the RFC 6724 deprecated attribute.  careOf is always false.
+func enumerateAddrCandidates() (cands []candidateAddr, err error) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("opening netlink socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	req := addrDumpRequest()
+	if err = unix.Sendto(sock, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("sending RTM_GETADDR: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, _, rErr := unix.Recvfrom(sock, buf, 0)
+		if rErr != nil {
+			return nil, fmt.Errorf("reading netlink response: %w", rErr)
+		}
+
+		msgs, pErr := unix.ParseNetlinkMessage(buf[:n])
+		if pErr != nil {
+			return nil, fmt.Errorf("parsing netlink messages: %w", pErr)
+		}
+
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case unix.NLMSG_DONE:
+				return cands, nil
+			case unix.RTM_NEWADDR:
+				if c, ok := candidateFromAddrMessage(m); ok {
+					cands = append(cands, c)
+				}
+			}
+		}
+	}
+}
+
+// addrDumpRequest builds the netlink request message asking the kernel to
+// dump every address on every interface, regardless of family.
+func addrDumpRequest() []byte {
+	hdrLen := unix.SizeofNlMsghdr
+	body := make([]byte, addrselectIfAddrMsgLen)
+
+	total := hdrLen + len(body)
+	buf := make([]byte, total)
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(total))
+	binary.LittleEndian.PutUint16(buf[4:6], unix.RTM_GETADDR)
+	binary.LittleEndian.PutUint16(buf[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	copy(buf[hdrLen:], body)
+
+	return buf
+}
+
+// candidateFromAddrMessage decodes a single RTM_NEWADDR dump entry into a
+// candidateAddr, reading its IFA_ADDRESS/IFA_LOCAL and IFA_FLAGS attributes.
+// Multicast addresses and entries without a usable address are skipped.
+func candidateFromAddrMessage(m unix.NetlinkMessage) (c candidateAddr, ok bool) {
+	if len(m.Data) < addrselectIfAddrMsgLen {
+		return candidateAddr{}, false
+	}
+
+	// The first byte of struct ifaddrmsg is ifa_family, the fifth is
+	// ifa_index; see rtnetlink(7).
+	index := int(binary.LittleEndian.Uint32(m.Data[4:8]))
+
+	// legacyFlags is ifa_flags, the third byte of struct ifaddrmsg; the
+	// IFA_FLAGS attribute, read below, is preferred when present since it
+	// carries flag bits (e.g. IFA_F_MANAGETEMPADDR) that don't fit in the
+	// legacy 8-bit field.
+	legacyFlags := uint32(m.Data[2])
+
+	attrs, err := unix.ParseNetlinkRouteAttr(&m)
+	if err != nil {
+		return candidateAddr{}, false
+	}
+
+	var ip net.IP
+	flags := legacyFlags
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case unix.IFA_ADDRESS, unix.IFA_LOCAL:
+			ip = net.IP(append([]byte(nil), a.Value...))
+		case unix.IFA_FLAGS:
+			flags = binary.LittleEndian.Uint32(a.Value)
+		}
+	}
+
+	if ip == nil || ip.IsMulticast() {
+		return candidateAddr{}, false
+	}
+
+	name := ""
+	if ifi, iErr := net.InterfaceByIndex(index); iErr == nil {
+		name = ifi.Name
+	}
+
+	return candidateAddr{
+		ip:         ip,
+		iface:      name,
+		ifaceIndex: index,
+		scope:      classifyScope(ip),
+		deprecated: flags&unix.IFA_F_DEPRECATED != 0,
+		// careOf is RFC 6275 Mobile IPv6 care-of vs. home address, not RFC
+		// 4941 privacy/temporary addresses: IFA_F_TEMPORARY marks the
+		// latter, so it doesn't belong here.  Plain, non-mobile hosts
+		// (everything Linux's netlink API actually reports a care-of flag
+		// for) never set a care-of address, so Rule 4 is left inert rather
+		// than mapped to the wrong flag.
+		careOf: false,
+	}, true
+}
+
+// outgoingInterface reports the name of the interface the kernel would
+// route dst out of, by issuing a targeted (non-dump) RTM_GETROUTE request
+// with RTA_DST set to dst and reading RTA_OIF back off the single route the
+// kernel replies with.
+func outgoingInterface(dst net.IP) (iface string, ok bool) {
+	family := unix.AF_INET
+	dstIP := dst.To4()
+	if dstIP == nil {
+		family = unix.AF_INET6
+		dstIP = dst.To16()
+	}
+
+	if dstIP == nil {
+		return "", false
+	}
+
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return "", false
+	}
+	defer unix.Close(sock)
+
+	req := routeLookupRequest(family, dstIP)
+	if err = unix.Sendto(sock, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return "", false
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	n, _, err := unix.Recvfrom(sock, buf, 0)
+	if err != nil {
+		return "", false
+	}
+
+	msgs, err := unix.ParseNetlinkMessage(buf[:n])
+	if err != nil {
+		return "", false
+	}
+
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWROUTE || len(m.Data) < rtMsgLen {
+			continue
+		}
+
+		attrs, aErr := unix.ParseNetlinkRouteAttr(&m)
+		if aErr != nil {
+			continue
+		}
+
+		for _, a := range attrs {
+			if a.Attr.Type != unix.RTA_OIF {
+				continue
+			}
+
+			oif := int(binary.LittleEndian.Uint32(a.Value))
+			if ifi, iErr := net.InterfaceByIndex(oif); iErr == nil {
+				return ifi.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// routeLookupRequest builds the netlink request message asking the kernel
+// to resolve the route it would use to reach dstIP, the family-appropriate
+// (4- or 16-byte) form of the destination address.
+func routeLookupRequest(family int, dstIP net.IP) []byte {
+	rtm := make([]byte, rtMsgLen)
+	rtm[0] = byte(family)
+	rtm[1] = byte(len(dstIP) * 8)
+
+	body := append([]byte{}, rtm...)
+	body = append(body, encodeRtattr(unix.RTA_DST, dstIP)...)
+
+	hdrLen := unix.SizeofNlMsghdr
+	total := hdrLen + len(body)
+	buf := make([]byte, total)
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(total))
+	binary.LittleEndian.PutUint16(buf[4:6], unix.RTM_GETROUTE)
+	binary.LittleEndian.PutUint16(buf[6:8], unix.NLM_F_REQUEST)
+	copy(buf[hdrLen:], body)
+
+	return buf
+}
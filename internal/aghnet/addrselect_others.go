@@ -0,0 +1,60 @@
+//go:build !linux
+
+package aghnet
+
+import "net"
+
+// enumerateAddrCandidates lists the unicast addresses of every interface on
+// the host using the portable net package.
+//
+// This is a best-effort fallback for platforms other than Linux: the
+// deprecated/careOf attributes require OS-specific address-flag lookups
+// (ifa_flags from getifaddrs on BSD/Darwin, OnLinkPrefixLength/dad_state
+// from GetAdaptersAddresses on Windows) that the portable net package
+// doesn't expose, so plain, healthy home addresses are assumed here, which
+// means RFC 6724 Rules 3 and 4 never change the ordering on these
+// platforms. See addrselect_linux.go for the platform that does read real
+// flags.
+func enumerateAddrCandidates() (cands []candidateAddr, err error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, aErr := ifi.Addrs()
+		if aErr != nil {
+			continue
+		}
+
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.IsMulticast() {
+				continue
+			}
+
+			cands = append(cands, candidateAddr{
+				ip:         ipNet.IP,
+				iface:      ifi.Name,
+				ifaceIndex: ifi.Index,
+				scope:      classifyScope(ipNet.IP),
+			})
+		}
+	}
+
+	return cands, nil
+}
+
+// outgoingInterface reports the interface the kernel would route dst out
+// of.  It's not implemented on this platform: doing so needs a
+// platform-specific route-lookup syscall (e.g. GetBestRoute2 on Windows, a
+// PF_ROUTE RTM_GET on BSD/Darwin, the same family gateway_windows.go and
+// gateway_bsd.go already use to find the default gateway), which hasn't
+// been wired up here, so RFC 6724 Rule 5 is inert on these platforms.
+func outgoingInterface(net.IP) (iface string, ok bool) {
+	return "", false
+}
@@ -0,0 +1,183 @@
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestAddrCandidates substitutes addrCandidatesFunc with one that
+// returns cands, restoring the previous value once the test finishes.  It's
+// the RFC 6724 analogue of testShell.set for the shell route fallback.  It also stubs
+// outgoingInterfaceFunc to report no outgoing interface, so that Rule 5
+// doesn't depend on the host's real routing table; use
+// setTestOutgoingInterface afterwards to exercise that rule specifically.
+func setTestAddrCandidates(t testing.TB, cands []candidateAddr) {
+	t.Helper()
+
+	prev := addrCandidatesFunc
+	t.Cleanup(func() { addrCandidatesFunc = prev })
+	addrCandidatesFunc = func() ([]candidateAddr, error) {
+		return cands, nil
+	}
+
+	setTestOutgoingInterface(t, "", false)
+}
+
+// setTestOutgoingInterface substitutes outgoingInterfaceFunc with one that
+// always returns iface, ok, restoring the previous value once the test
+// finishes.
+func setTestOutgoingInterface(t testing.TB, iface string, ok bool) {
+	t.Helper()
+
+	prev := outgoingInterfaceFunc
+	t.Cleanup(func() { outgoingInterfaceFunc = prev })
+	outgoingInterfaceFunc = func(net.IP) (string, bool) {
+		return iface, ok
+	}
+}
+
+func TestSelectSourceAddr(t *testing.T) {
+	globalV4 := candidateAddr{
+		ip:    net.ParseIP("203.0.113.10"),
+		iface: "eth0",
+		scope: scopeGlobal,
+	}
+	siteV4 := candidateAddr{
+		ip:    net.ParseIP("192.168.1.10"),
+		iface: "eth0",
+		scope: scopeSiteLocal,
+	}
+	globalV6 := candidateAddr{
+		ip:    net.ParseIP("2001:db8::10"),
+		iface: "eth0",
+		scope: scopeGlobal,
+	}
+	linkV6 := candidateAddr{
+		ip:    net.ParseIP("fe80::1"),
+		iface: "eth0",
+		scope: scopeLinkLocal,
+	}
+	deprecatedV6 := candidateAddr{
+		ip:         net.ParseIP("2001:db8::dead"),
+		iface:      "eth0",
+		scope:      scopeGlobal,
+		deprecated: true,
+	}
+	careOfV6 := candidateAddr{
+		ip:     net.ParseIP("2001:db8::c0:1"),
+		iface:  "eth0",
+		scope:  scopeGlobal,
+		careOf: true,
+	}
+
+	testCases := []struct {
+		name  string
+		cands []candidateAddr
+		dst   net.IP
+		want  net.IP
+	}{{
+		name:  "prefers_matching_scope_v4",
+		cands: []candidateAddr{siteV4, globalV4},
+		dst:   net.ParseIP("203.0.113.1"),
+		want:  globalV4.ip,
+	}, {
+		name:  "prefers_link_local_for_link_local_dst",
+		cands: []candidateAddr{globalV6, linkV6},
+		dst:   net.ParseIP("fe80::abcd"),
+		want:  linkV6.ip,
+	}, {
+		name:  "avoids_deprecated",
+		cands: []candidateAddr{deprecatedV6, globalV6},
+		dst:   net.ParseIP("2001:db8::1"),
+		want:  globalV6.ip,
+	}, {
+		name:  "prefers_home_over_careof",
+		cands: []candidateAddr{careOfV6, globalV6},
+		dst:   net.ParseIP("2001:db8::1"),
+		want:  globalV6.ip,
+	}, {
+		name:  "ignores_other_family",
+		cands: []candidateAddr{globalV4, globalV6},
+		dst:   net.ParseIP("2001:db8::1"),
+		want:  globalV6.ip,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestAddrCandidates(t, tc.cands)
+
+			src, iface, err := SelectSourceAddr(tc.dst)
+			require.NoError(t, err)
+
+			assert.True(t, tc.want.Equal(src), "got %s, want %s", src, tc.want)
+			assert.NotEmpty(t, iface)
+		})
+	}
+
+	t.Run("no_candidates", func(t *testing.T) {
+		setTestAddrCandidates(t, nil)
+
+		_, _, err := SelectSourceAddr(net.ParseIP("203.0.113.1"))
+		assert.Error(t, err)
+	})
+
+	t.Run("prefers_outgoing_interface", func(t *testing.T) {
+		// other shares a longer prefix with the destination than globalV6
+		// does, so only Rule 5 makes globalV6 win.
+		other := candidateAddr{
+			ip:    net.ParseIP("2001:db8::3"),
+			iface: "eth1",
+			scope: scopeGlobal,
+		}
+
+		setTestAddrCandidates(t, []candidateAddr{other, globalV6})
+		setTestOutgoingInterface(t, globalV6.iface, true)
+
+		src, iface, err := SelectSourceAddr(net.ParseIP("2001:db8::1"))
+		require.NoError(t, err)
+
+		assert.True(t, globalV6.ip.Equal(src), "got %s, want %s", src, globalV6.ip)
+		assert.Equal(t, globalV6.iface, iface)
+	})
+}
+
+func TestClassifyScope(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   net.IP
+		want addrScope
+	}{{
+		name: "loopback_v4",
+		ip:   net.ParseIP("127.0.0.1"),
+		want: scopeInterfaceLocal,
+	}, {
+		name: "link_local_v6",
+		ip:   net.ParseIP("fe80::1"),
+		want: scopeLinkLocal,
+	}, {
+		name: "private_v4",
+		ip:   net.ParseIP("10.1.2.3"),
+		want: scopeSiteLocal,
+	}, {
+		name: "unique_local_v6",
+		ip:   net.ParseIP("fd00::1"),
+		want: scopeSiteLocal,
+	}, {
+		name: "global_v4",
+		ip:   net.ParseIP("8.8.8.8"),
+		want: scopeGlobal,
+	}, {
+		name: "global_v6",
+		ip:   net.ParseIP("2001:4860:4860::8888"),
+		want: scopeGlobal,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, classifyScope(tc.ip))
+		})
+	}
+}
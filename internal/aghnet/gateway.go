@@ -0,0 +1,38 @@
+package aghnet
+
+import (
+	"net"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// errNoGateway is returned by the platform-specific route lookups when no
+// matching default route could be found.
+const errNoGateway errors.Error = "no default route found"
+
+// GatewayIPs returns the IPv4 and IPv6 addresses of the default gateway
+// bound to the network interface with the given name, as reported by the
+// OS routing table.  Either of the returned addresses may be nil if the
+// corresponding default route doesn't exist or doesn't have a gateway, and
+// both are nil if iface is empty, unknown, or the lookup fails.
+func GatewayIPs(iface string) (v4, v6 net.IP) {
+	if iface == "" {
+		return nil, nil
+	}
+
+	return gatewayIPs(iface)
+}
+
+// GatewayIP returns the IPv4 gateway address bound to the network interface
+// with the given name.  If there is no IPv4 gateway, it returns the IPv6 one,
+// or nil if neither could be determined.
+//
+// Deprecated: Use GatewayIPs instead.
+func GatewayIP(iface string) (ip net.IP) {
+	v4, v6 := GatewayIPs(iface)
+	if v4 != nil {
+		return v4
+	}
+
+	return v6
+}
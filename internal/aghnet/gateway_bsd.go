@@ -0,0 +1,198 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// gatewayIPs opens a PF_ROUTE socket, issues an RTM_GET for the IPv4 and
+// IPv6 default destinations, and pulls the gateway out of the returned
+// sockaddr array (RTAX_GATEWAY), filtering to routes bound to iface.
+func gatewayIPs(iface string) (v4, v6 net.IP) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, nil
+	}
+
+	if ip, rErr := requestRoute(unix.AF_INET, net.IPv4zero, ifi.Index); rErr == nil {
+		v4 = ip
+	}
+
+	if ip, rErr := requestRoute(unix.AF_INET6, net.IPv6unspecified, ifi.Index); rErr == nil {
+		v6 = ip
+	}
+
+	return v4, v6
+}
+
+// requestRoute opens a PF_ROUTE/SOCK_RAW socket, sends an RTM_GET for dst,
+// and extracts RTAX_GATEWAY from the reply if the route is bound to the
+// interface with the given index.
+func requestRoute(family int, dst net.IP, ifIndex int) (gw net.IP, err error) {
+	sock, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, family)
+	if err != nil {
+		return nil, fmt.Errorf("opening route socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	msg := routeGetMessage(family, dst, ifIndex)
+	if _, err = unix.Write(sock, msg); err != nil {
+		return nil, fmt.Errorf("sending RTM_GET: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	n, err := unix.Read(sock, buf)
+	if err != nil {
+		return nil, fmt.Errorf("reading route socket reply: %w", err)
+	}
+
+	reply := buf[:n]
+	if len(reply) >= rtmHdrLen {
+		replyIndex := int(binary.LittleEndian.Uint16(reply[rtmIndexOffset : rtmIndexOffset+2]))
+		if replyIndex != ifIndex {
+			return nil, errNoGateway
+		}
+	}
+
+	return gatewayFromRouteReply(reply)
+}
+
+// rtmHdrLen is the size, in bytes, of the fixed part of a struct rt_msghdr
+// on BSD systems; see route(4):
+//
+//	u_short rtm_msglen;  // 0
+//	u_char  rtm_version; // 2
+//	u_char  rtm_type;    // 3
+//	u_short rtm_index;   // 4
+//	(2 bytes of padding so rtm_flags is 4-byte aligned)
+//	int     rtm_flags;   // 8
+//	int     rtm_addrs;   // 12
+const rtmHdrLen = 92
+
+// rtmIndexOffset and rtmAddrsOffset are the byte offsets of rtm_index and
+// rtm_addrs within a struct rt_msghdr.
+const (
+	rtmIndexOffset = 4
+	rtmAddrsOffset = 12
+)
+
+// routeGetMessage builds an RTM_GET request for dst bound to the interface
+// with the given index, with the RTA_DST sockaddr for dst appended after
+// the rt_msghdr, matching the RTA_DST bit set in rtm_addrs.
+func routeGetMessage(family int, dst net.IP, ifIndex int) []byte {
+	dstSockaddr := encodeDstSockaddr(family, dst)
+
+	total := rtmHdrLen + len(dstSockaddr)
+	buf := make([]byte, total)
+
+	binary.LittleEndian.PutUint16(buf[0:2], uint16(total))
+	buf[2] = unix.RTM_VERSION
+	buf[3] = unix.RTM_GET
+	binary.LittleEndian.PutUint16(buf[rtmIndexOffset:rtmIndexOffset+2], uint16(ifIndex))
+	binary.LittleEndian.PutUint32(buf[8:12], 0)
+	binary.LittleEndian.PutUint32(buf[rtmAddrsOffset:rtmAddrsOffset+4], unix.RTA_DST|unix.RTA_IFP)
+
+	copy(buf[rtmHdrLen:], dstSockaddr)
+
+	return buf
+}
+
+// encodeDstSockaddr builds the struct sockaddr carrying dst, in the same
+// layout parseSockaddr reads back: family at byte 1, then the address at
+// byte 4 (4 bytes, AF_INET) or byte 8 (16 bytes, AF_INET6).
+func encodeDstSockaddr(family int, dst net.IP) []byte {
+	if family == unix.AF_INET6 {
+		b := make([]byte, 32)
+		b[0] = 28
+		b[1] = unix.AF_INET6
+		copy(b[8:24], dst.To16())
+
+		return b
+	}
+
+	b := make([]byte, 16)
+	b[0] = 16
+	b[1] = unix.AF_INET
+	copy(b[4:8], dst.To4())
+
+	return b
+}
+
+// gatewayFromRouteReply walks the sockaddr array appended after the
+// rt_msghdr looking for RTAX_GATEWAY.
+func gatewayFromRouteReply(b []byte) (gw net.IP, err error) {
+	if len(b) < rtmHdrLen {
+		return nil, errNoGateway
+	}
+
+	addrsBitmap := binary.LittleEndian.Uint32(b[rtmAddrsOffset : rtmAddrsOffset+4])
+	rest := b[rtmHdrLen:]
+
+	for i := 0; i < rtaxMax; i++ {
+		bit := uint32(1) << uint(i)
+		if addrsBitmap&bit == 0 {
+			continue
+		}
+
+		if len(rest) == 0 {
+			break
+		}
+
+		saLen := int(rest[0])
+		if saLen == 0 {
+			saLen = 4
+		}
+
+		if i == rtaxGateway && saLen >= 4 {
+			gw = parseSockaddr(rest[:saLen])
+		}
+
+		if saLen > len(rest) {
+			break
+		}
+
+		rest = rest[saLen:]
+	}
+
+	if gw == nil {
+		return nil, errNoGateway
+	}
+
+	return gw, nil
+}
+
+// Indices into the RTAX address array; see route(4).
+const (
+	rtaxDst = iota
+	rtaxGateway
+	rtaxNetmask
+	rtaxGenmask
+	rtaxIfp
+	rtaxIfa
+	rtaxAuthor
+	rtaxBrd
+	rtaxMax
+)
+
+// parseSockaddr extracts the IP address out of a raw struct sockaddr as
+// found in a PF_ROUTE message.
+func parseSockaddr(b []byte) net.IP {
+	if len(b) < 2 {
+		return nil
+	}
+
+	family := b[1]
+	switch {
+	case family == unix.AF_INET && len(b) >= 8:
+		return net.IP(append([]byte(nil), b[4:8]...))
+	case family == unix.AF_INET6 && len(b) >= 24:
+		return net.IP(append([]byte(nil), b[8:24]...))
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+//go:build linux
+
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// gatewayIPs asks the kernel's routing table for the default route bound to
+// iface, using an AF_NETLINK/NETLINK_ROUTE RTM_GETROUTE request, and returns
+// the gateway addresses found in the RTA_GATEWAY attributes of the routes
+// with dst_len == 0 whose RTA_OIF matches iface's index.
+func gatewayIPs(iface string) (v4, v6 net.IP) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, nil
+	}
+
+	if ip, rErr := requestDefaultRoute(unix.AF_INET, ifi.Index); rErr == nil {
+		v4 = ip
+	}
+
+	if ip, rErr := requestDefaultRoute(unix.AF_INET6, ifi.Index); rErr == nil {
+		v6 = ip
+	}
+
+	return v4, v6
+}
+
+// requestDefaultRoute performs a single RTM_GETROUTE dump for family over a
+// NETLINK_ROUTE socket and returns the gateway of the default route
+// (dst_len == 0) bound to the interface with index oif.
+func requestDefaultRoute(family int, oif int) (gw net.IP, err error) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("opening netlink socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	req := routeDumpRequest(family)
+	if err = unix.Sendto(sock, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("sending RTM_GETROUTE: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, _, rErr := unix.Recvfrom(sock, buf, 0)
+		if rErr != nil {
+			return nil, fmt.Errorf("reading netlink response: %w", rErr)
+		}
+
+		msgs, pErr := unix.ParseNetlinkMessage(buf[:n])
+		if pErr != nil {
+			return nil, fmt.Errorf("parsing netlink messages: %w", pErr)
+		}
+
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case unix.NLMSG_DONE:
+				return nil, errNoGateway
+			case unix.NLMSG_ERROR:
+				return nil, fmt.Errorf("netlink: kernel returned an error message")
+			case unix.RTM_NEWROUTE:
+				if ip, ok := gatewayFromRouteMessage(m, oif); ok {
+					return ip, nil
+				}
+			}
+		}
+	}
+}
+
+// rtMsgLen is the size, in bytes, of the fixed part of a struct rtmsg; see
+// rtnetlink(7).
+const rtMsgLen = 12
+
+// gatewayFromRouteMessage extracts the RTA_GATEWAY attribute from m if it
+// describes a default route (dst_len == 0) bound to the interface with
+// index oif.
+func gatewayFromRouteMessage(m unix.NetlinkMessage, oif int) (gw net.IP, ok bool) {
+	if len(m.Data) < rtMsgLen {
+		return nil, false
+	}
+
+	// The second byte of struct rtmsg is rtm_dst_len; see rtnetlink(7).
+	dstLen := m.Data[1]
+	if dstLen != 0 {
+		// Not a default route.
+		return nil, false
+	}
+
+	attrs, err := unix.ParseNetlinkRouteAttr(&m)
+	if err != nil {
+		return nil, false
+	}
+
+	var haveOif bool
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case unix.RTA_OIF:
+			if int(binary.LittleEndian.Uint32(a.Value)) == oif {
+				haveOif = true
+			}
+		case unix.RTA_GATEWAY:
+			gw = net.IP(append([]byte(nil), a.Value...))
+		}
+	}
+
+	if !haveOif || gw == nil {
+		return nil, false
+	}
+
+	return gw, true
+}
+
+// routeDumpRequest builds the netlink request message asking the kernel to
+// dump all routes of the given address family.
+func routeDumpRequest(family int) []byte {
+	hdrLen := unix.SizeofNlMsghdr
+	body := make([]byte, rtMsgLen)
+	body[0] = byte(family)
+
+	total := hdrLen + len(body)
+	buf := make([]byte, total)
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(total))
+	binary.LittleEndian.PutUint16(buf[4:6], unix.RTM_GETROUTE)
+	binary.LittleEndian.PutUint16(buf[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	binary.LittleEndian.PutUint32(buf[8:12], 0)
+	binary.LittleEndian.PutUint32(buf[12:16], 0)
+	copy(buf[hdrLen:], body)
+
+	return buf
+}
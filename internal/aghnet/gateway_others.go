@@ -0,0 +1,10 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package aghnet
+
+import "net"
+
+// gatewayIPs is unimplemented on this platform.
+func gatewayIPs(iface string) (v4, v6 net.IP) {
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/windows"
+)
+
+// gatewayIPs looks up the interface's LUID and calls GetBestRoute2 on that
+// LUID for the unspecified IPv4 and IPv6 destinations, returning the next
+// hops of the best routes found.
+func gatewayIPs(iface string) (v4, v6 net.IP) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, nil
+	}
+
+	luid, err := luidFromIndex(uint32(ifi.Index))
+	if err != nil {
+		return nil, nil
+	}
+
+	if ip, bErr := bestRoute(windows.AF_INET, luid); bErr == nil {
+		v4 = ip
+	}
+
+	if ip, bErr := bestRoute(windows.AF_INET6, luid); bErr == nil {
+		v6 = ip
+	}
+
+	return v4, v6
+}
+
+// luidFromIndex converts an interface index into the LUID expected by
+// GetBestRoute2.
+func luidFromIndex(index uint32) (luid uint64, err error) {
+	var winLUID windows.LUID
+	if cErr := windows.ConvertInterfaceIndexToLuid(index, &winLUID); cErr != nil {
+		return 0, fmt.Errorf("converting interface index to LUID: %w", cErr)
+	}
+
+	return uint64(winLUID.HighPart)<<32 | uint64(winLUID.LowPart), nil
+}
+
+// bestRoute calls GetBestRoute2 for the given address family and interface
+// LUID and returns the next-hop gateway address of the result.
+func bestRoute(family uint16, luid uint64) (gw net.IP, err error) {
+	ip, err := windowsGetBestRoute2(family, luid)
+	if err != nil {
+		return nil, fmt.Errorf("GetBestRoute2: %w", err)
+	}
+
+	if ip == nil {
+		return nil, errNoGateway
+	}
+
+	return ip, nil
+}
@@ -0,0 +1,73 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modIphlpapi       = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetBestRoute2 = modIphlpapi.NewProc("GetBestRoute2")
+)
+
+// sockaddrInet mirrors the union SOCKADDR_INET from ws2ipdef.h; it's large
+// enough to hold either a sockaddr_in or a sockaddr_in6.
+type sockaddrInet struct {
+	family uint16
+	data   [26]byte
+}
+
+// mibIPforwardRow2 mirrors enough of the MIB_IPFORWARD_ROW2 structure for
+// GetBestRoute2's output parameter; unused trailing fields are left as raw
+// padding since we only read NextHop.
+type mibIPforwardRow2 struct {
+	interfaceLuid     uint64
+	interfaceIndex    uint32
+	destinationPrefix [32]byte
+	nextHop           sockaddrInet
+	_                 [64]byte
+}
+
+// windowsGetBestRoute2 calls the iphlpapi GetBestRoute2 function for the
+// interface with the given LUID and address family, returning the next-hop
+// gateway address of the best route, or nil if there is none.
+func windowsGetBestRoute2(family uint16, luid uint64) (gw net.IP, err error) {
+	var dest sockaddrInet
+	dest.family = family
+
+	var best mibIPforwardRow2
+	var bestSrc sockaddrInet
+
+	ret, _, _ := procGetBestRoute2.Call(
+		uintptr(unsafe.Pointer(&luid)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&dest)),
+		0,
+		uintptr(unsafe.Pointer(&best)),
+		uintptr(unsafe.Pointer(&bestSrc)),
+	)
+
+	if ret != 0 {
+		return nil, syscall.Errno(ret)
+	}
+
+	return sockaddrInetIP(best.nextHop), nil
+}
+
+// sockaddrInetIP extracts the net.IP encoded in a SOCKADDR_INET.
+func sockaddrInetIP(sa sockaddrInet) net.IP {
+	switch sa.family {
+	case windows.AF_INET:
+		return net.IP(sa.data[2:6])
+	case windows.AF_INET6:
+		return net.IP(sa.data[6:22])
+	default:
+		return nil
+	}
+}
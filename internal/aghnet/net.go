@@ -0,0 +1,204 @@
+// Package aghnet contains network-related utilities.
+package aghnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// aghosRunCommand is a wrapper around exec.Command that's used to simplify
+// testing of the code that shells out.
+var aghosRunCommand = runCommand
+
+// runCommand runs cmd with args and returns its exit code, standard output,
+// and any error encountered while starting or waiting for it.
+func runCommand(cmd string, args ...string) (code int, output []byte, err error) {
+	c := exec.Command(cmd, args...)
+
+	output, err = c.Output()
+	if err != nil {
+		exitErr := &exec.ExitError{}
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), output, nil
+		}
+
+		return 1, nil, fmt.Errorf("executing %q: %w", cmd, err)
+	}
+
+	return 0, output, nil
+}
+
+// NetInterface represents a network interface as reported to the frontend
+// and used by the rest of the application.
+type NetInterface struct {
+	// Name is the name of the network interface.
+	Name string `json:"name"`
+
+	// MTU is the network interface's maximum transmission unit.
+	MTU int `json:"mtu"`
+
+	// HardwareAddr is the network interface's hardware address.
+	HardwareAddr string `json:"hardware_address"`
+
+	// Addresses are the IP addresses bound to the interface.
+	Addresses []net.IP `json:"ip_addresses"`
+
+	// Subnets are the subnets to which the addresses in Addresses belong.
+	Subnets []*net.IPNet `json:"-"`
+
+	// Flags are the interface's flags.
+	Flags net.Flags `json:"flags"`
+}
+
+// GetValidNetInterfacesForWeb returns interfaces that are eligible for the
+// administrative web panel.  It excludes loopback and down interfaces, as
+// well as those without any usable addresses.
+func GetValidNetInterfacesForWeb() (netIfaces []*NetInterface, err error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("getting net interfaces: %w", err)
+	}
+
+	if len(ifaces) == 0 {
+		return nil, errors.Error("couldn't find any legible network interfaces")
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, aErr := iface.Addrs()
+		if aErr != nil {
+			return nil, fmt.Errorf("getting addresses for net interface %s: %w", iface.Name, aErr)
+		}
+
+		netIface := &NetInterface{
+			Name:         iface.Name,
+			MTU:          iface.MTU,
+			HardwareAddr: iface.HardwareAddr.String(),
+			Flags:        iface.Flags,
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			netIface.Addresses = append(netIface.Addresses, ipNet.IP)
+			netIface.Subnets = append(netIface.Subnets, ipNet)
+		}
+
+		if len(netIface.Addresses) == 0 {
+			continue
+		}
+
+		netIfaces = append(netIfaces, netIface)
+	}
+
+	return netIfaces, nil
+}
+
+// GetInterfaceByIP returns the name of the interface that owns ip, or an
+// empty string if none of the interfaces returned by
+// GetValidNetInterfacesForWeb owns it.
+func GetInterfaceByIP(ip net.IP) string {
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil {
+		return ""
+	}
+
+	for _, iface := range ifaces {
+		for _, addr := range iface.Addresses {
+			if addr.Equal(ip) {
+				return iface.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// BroadcastFromIPNet calculates the broadcast IP address for subnet.  If
+// subnet's mask is nil, the default mask of subnet.IP's address class is
+// used.  IPv6 subnets, and IPv4 addresses without a default mask, are
+// returned as-is.
+func BroadcastFromIPNet(subnet *net.IPNet) (bc net.IP) {
+	ip4 := subnet.IP.To4()
+	if ip4 == nil {
+		return subnet.IP
+	}
+
+	mask := subnet.Mask
+	if len(mask) == 0 {
+		mask = ip4.DefaultMask()
+	}
+
+	if len(mask) == 0 {
+		return subnet.IP
+	}
+
+	bc = make(net.IP, len(ip4))
+	for i, b := range ip4 {
+		bc[i] = b | ^mask[i]
+	}
+
+	return bc
+}
+
+// CollectAllIfacesAddrs returns the slice of all network interfaces' IP
+// addresses, as a string, without the port number.
+func CollectAllIfacesAddrs() (addrs []string, err error) {
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("getting interface addresses: %w", err)
+	}
+
+	for _, a := range ifaceAddrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		addrs = append(addrs, ipNet.IP.String())
+	}
+
+	return addrs, nil
+}
+
+// CheckPort checks if the port is available for binding on the given
+// network ("tcp" or "udp") and IP address.  If network isn't "tcp" or "udp",
+// CheckPort does nothing and returns nil.
+func CheckPort(network string, ip net.IP, port int) (err error) {
+	var c io.Closer
+	switch network {
+	case "tcp":
+		var l net.Listener
+		l, err = net.Listen("tcp", net.JoinHostPort(ip.String(), fmt.Sprintf("%d", port)))
+		c = l
+	case "udp":
+		var conn net.PacketConn
+		conn, err = net.ListenPacket("udp", net.JoinHostPort(ip.String(), fmt.Sprintf("%d", port)))
+		c = conn
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c != nil {
+		if cErr := c.Close(); cErr != nil {
+			log.Error("aghnet: closing listener: %s", cErr)
+		}
+	}
+
+	return nil
+}
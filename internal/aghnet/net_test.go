@@ -1,9 +1,7 @@
 package aghnet
 
 import (
-	"io/fs"
 	"net"
-	"os"
 	"strings"
 	"testing"
 
@@ -19,17 +17,6 @@ func TestMain(m *testing.M) {
 	aghtest.DiscardLogOutput(m)
 }
 
-// testdata is the filesystem containing data for testing the package.
-var testdata fs.FS = os.DirFS("./testdata")
-
-func setTestRootDirFS(t testing.TB, fsys fs.FS) {
-	prev := rootDirFS
-	t.Cleanup(func() {
-		rootDirFS = prev
-	})
-	rootDirFS = fsys
-}
-
 // testShell is a substitution of aghos.RunCommand that maps the command to it's
 // execution result.  It's only needed to simplify testing.
 //
@@ -54,62 +41,22 @@ func (rc testShell) set(t testing.TB) {
 }
 
 func TestGatewayIP(t *testing.T) {
-	testCases := []struct {
-		name string
-		rcs  testShell
-		want net.IP
-	}{{
-		name: "success_v4",
-		rcs: testShell{"ip route show dev ifaceName": {
-			err:  nil,
-			out:  `default via 1.2.3.4 onlink`,
-			code: 0,
-		}},
-		want: net.IP{1, 2, 3, 4}.To16(),
-	}, {
-		name: "success_v6",
-		rcs: testShell{"ip route show dev ifaceName": {
-			err:  nil,
-			out:  `default via ::ffff onlink`,
-			code: 0,
-		}},
-		want: net.IP{
-			0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-			0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xFF, 0xFF,
-		},
-	}, {
-		name: "bad_output",
-		rcs: testShell{"ip route show dev ifaceName": {
-			err:  nil,
-			out:  `non-default via 1.2.3.4 onlink`,
-			code: 0,
-		}},
-		want: nil,
-	}, {
-		name: "err_runcmd",
-		rcs: testShell{"ip route show dev ifaceName": {
-			err:  errors.Error("can't run command"),
-			out:  ``,
-			code: 0,
-		}},
-		want: nil,
-	}, {
-		name: "bad_code",
-		rcs: testShell{"ip route show dev ifaceName": {
-			err:  nil,
-			out:  ``,
-			code: 1,
-		}},
-		want: nil,
-	}}
+	t.Run("empty_iface", func(t *testing.T) {
+		assert.Nil(t, GatewayIP(""))
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			tc.rcs.set(t)
+		v4, v6 := GatewayIPs("")
+		assert.Nil(t, v4)
+		assert.Nil(t, v6)
+	})
 
-			assert.Equal(t, tc.want, GatewayIP("ifaceName"))
-		})
-	}
+	t.Run("unknown_iface", func(t *testing.T) {
+		// GatewayIPs now talks to the kernel's routing table directly, so
+		// there is no shell command left to stub out; an interface that
+		// doesn't exist is the only case that's environment-independent.
+		v4, v6 := GatewayIPs("aghnet-test-nonexistent-iface")
+		assert.Nil(t, v4)
+		assert.Nil(t, v6)
+	})
 }
 
 func TestGetInterfaceByIP(t *testing.T) {
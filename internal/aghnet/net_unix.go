@@ -0,0 +1,19 @@
+//go:build !windows
+
+package aghnet
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsAddrInUse returns true if err is a system call error that signals that
+// an address is already in use.
+func IsAddrInUse(err error) (ok bool) {
+	var sysErr syscall.Errno
+	if !errors.As(err, &sysErr) {
+		return false
+	}
+
+	return sysErr == syscall.EADDRINUSE
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errWSAEADDRINUSE is the Windows socket error code for "address already in
+// use".
+const errWSAEADDRINUSE syscall.Errno = 10048
+
+// IsAddrInUse returns true if err is a system call error that signals that
+// an address is already in use.
+func IsAddrInUse(err error) (ok bool) {
+	var sysErr syscall.Errno
+	if !errors.As(err, &sysErr) {
+		return false
+	}
+
+	return sysErr == errWSAEADDRINUSE
+}
@@ -0,0 +1,152 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// errNotImplemented is returned by RouteTable operations that haven't been
+// implemented on the current platform yet.
+const errNotImplemented errors.Error = "aghnet: not implemented on this platform"
+
+// Route is a single entry of a RouteTable: the route to Dst via Gw over
+// Iface, with the given Metric.
+type Route struct {
+	Dst    *net.IPNet
+	Gw     net.IP
+	Iface  string
+	Metric int
+}
+
+// String implements the fmt.Stringer interface for *Route.
+func (r *Route) String() (s string) {
+	if r == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("%s via %s dev %s metric %d", r.Dst, r.Gw, r.Iface, r.Metric)
+}
+
+// trackedRoute is a Route together with the number of DHCP leases (or other
+// callers) that currently need it installed.
+type trackedRoute struct {
+	route Route
+	count int
+}
+
+// RouteTable manages the host's static routes on behalf of the DHCP server
+// and the admin UI, reference-counting them so that a route shared by
+// several leases is only removed from the kernel once the last lease that
+// needs it is gone.
+//
+// A zero RouteTable is not valid; use NewRouteTable.
+type RouteTable struct {
+	mu   sync.Mutex
+	refs map[string]*trackedRoute
+}
+
+// NewRouteTable returns a new, empty *RouteTable.
+func NewRouteTable() (rt *RouteTable) {
+	return &RouteTable{
+		refs: map[string]*trackedRoute{},
+	}
+}
+
+// routeKey returns the map key identifying the route to dst via gw over iface.
+// The metric isn't a part of a route's identity for reference-counting
+// purposes: two Add calls for the same dst/gw/iface share a single kernel
+// route even if they ask for different metrics.
+func routeKey(dst *net.IPNet, gw net.IP, iface string) (key string) {
+	return fmt.Sprintf("%s/%s/%s", dst, gw, iface)
+}
+
+// Add installs a route to dst via gw over iface with the given metric.  If
+// an equivalent route has already been added and not yet fully released by
+// matching Del calls, Add only increments its reference count and doesn't
+// touch the kernel again.
+func (rt *RouteTable) Add(dst *net.IPNet, gw net.IP, iface string, metric int) (err error) {
+	if dst == nil {
+		return errors.Error("aghnet: route destination must not be nil")
+	}
+
+	key := routeKey(dst, gw, iface)
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if tr, ok := rt.refs[key]; ok {
+		tr.count++
+
+		return nil
+	}
+
+	r := Route{Dst: dst, Gw: gw, Iface: iface, Metric: metric}
+	if err = routeAdd(r); err != nil {
+		return fmt.Errorf("aghnet: adding route %s: %w", &r, err)
+	}
+
+	rt.refs[key] = &trackedRoute{route: r, count: 1}
+
+	return nil
+}
+
+// Del releases a reference to the route to dst via gw over iface.  The
+// route is only actually removed from the kernel once its reference count
+// drops to zero.
+func (rt *RouteTable) Del(dst *net.IPNet, gw net.IP, iface string) (err error) {
+	key := routeKey(dst, gw, iface)
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	tr, ok := rt.refs[key]
+	if !ok {
+		return fmt.Errorf("aghnet: route %s isn't tracked", key)
+	}
+
+	if tr.count > 1 {
+		tr.count--
+
+		return nil
+	}
+
+	if err = routeDel(tr.route); err != nil {
+		return fmt.Errorf("aghnet: deleting route %s: %w", &tr.route, err)
+	}
+
+	delete(rt.refs, key)
+
+	return nil
+}
+
+// List returns the routes currently installed on the host.  It only works
+// on Linux today: on BSD/Darwin and Windows it returns errNotImplemented,
+// since listRoutes there would mean parsing "netstat -rn"'s or "route
+// print"'s platform-specific, version-dependent text output, which hasn't
+// been done yet; see the listRoutes doc comments in route_bsd.go and
+// route_windows.go.
+func (rt *RouteTable) List() (routes []Route, err error) {
+	return listRoutes()
+}
+
+// Close removes every route rt is still tracking, regardless of its
+// reference count, so that a clean shutdown doesn't leave stale routes
+// behind.
+func (rt *RouteTable) Close() (err error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for key, tr := range rt.refs {
+		if dErr := routeDel(tr.route); dErr != nil {
+			log.Error("aghnet: removing route %s on close: %s", &tr.route, dErr)
+		}
+
+		delete(rt.refs, key)
+	}
+
+	return nil
+}
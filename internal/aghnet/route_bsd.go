@@ -0,0 +1,70 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+)
+
+// bsdNetmask renders r.Dst's mask the way the BSD route(8) utility expects
+// it: a dotted-quad for IPv4, or the mask's own string form for IPv6.
+func bsdNetmask(r Route) (mask string) {
+	if r.Dst.IP.To4() != nil {
+		ones, _ := r.Dst.Mask.Size()
+
+		return net.IP(net.CIDRMask(ones, 32)).String()
+	}
+
+	return r.Dst.Mask.String()
+}
+
+// routeAdd installs r by shelling out to "route add".  A PF_ROUTE/
+// RTM_ADD socket backend, mirroring the read path in gateway_bsd.go, would
+// avoid the dependency on the route(8) binary; it's left as a follow-up.
+func routeAdd(r Route) (err error) {
+	return shellRouteBSD(shellRouteAddArgsBSD(r))
+}
+
+// routeDel removes r by shelling out to "route delete".
+func routeDel(r Route) (err error) {
+	return shellRouteBSD(shellRouteDelArgsBSD(r))
+}
+
+// listRoutes is not implemented on BSD/Darwin yet: parsing "netstat -rn"
+// reliably across Darwin, FreeBSD, NetBSD, OpenBSD and DragonFly's differing
+// output formats is substantial follow-up work of its own.
+func listRoutes() (routes []Route, err error) {
+	return nil, errNotImplemented
+}
+
+// shellRouteBSD runs a "route" command built by shellRouteAddArgsBSD or
+// shellRouteDelArgsBSD.
+func shellRouteBSD(args []string) (err error) {
+	code, out, err := aghosRunCommand(args[0], args[1:]...)
+	if err != nil {
+		return fmt.Errorf("running %q: %w", args, err)
+	}
+
+	if code != 0 {
+		return fmt.Errorf("running %q: exit code %d: %s", args, code, out)
+	}
+
+	return nil
+}
+
+// shellRouteAddArgsBSD builds the "route add -net <dst> <gw> <netmask>"
+// command line.
+func shellRouteAddArgsBSD(r Route) (args []string) {
+	mask := bsdNetmask(r)
+
+	return []string{"route", "add", "-net", r.Dst.IP.String(), r.Gw.String(), mask}
+}
+
+// shellRouteDelArgsBSD builds the "route delete -net <dst> <gw> <netmask>"
+// command line.
+func shellRouteDelArgsBSD(r Route) (args []string) {
+	mask := bsdNetmask(r)
+
+	return []string{"route", "delete", "-net", r.Dst.IP.String(), r.Gw.String(), mask}
+}
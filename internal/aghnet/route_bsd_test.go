@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellRouteArgsBSD(t *testing.T) {
+	r := Route{
+		Dst:   &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Gw:    net.ParseIP("166.111.8.1"),
+		Iface: "en0",
+	}
+
+	assert.Equal(t, []string{
+		"route", "add", "-net", "0.0.0.0", "166.111.8.1", "0.0.0.0",
+	}, shellRouteAddArgsBSD(r))
+
+	assert.Equal(t, []string{
+		"route", "delete", "-net", "0.0.0.0", "166.111.8.1", "0.0.0.0",
+	}, shellRouteDelArgsBSD(r))
+}
@@ -0,0 +1,303 @@
+//go:build linux
+
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// routeAdd installs r via a NETLINK_ROUTE RTM_NEWROUTE request, falling
+// back to shelling out to "ip route add" if the netlink request fails, e.g.
+// because the process lacks CAP_NET_ADMIN.
+func routeAdd(r Route) (err error) {
+	if nErr := netlinkRouteOp(unix.RTM_NEWROUTE, unix.NLM_F_CREATE|unix.NLM_F_EXCL, r); nErr == nil {
+		return nil
+	}
+
+	return shellRoute(shellRouteAddArgs(r))
+}
+
+// routeDel removes r via a NETLINK_ROUTE RTM_DELROUTE request, falling back
+// to "ip route del" the same way routeAdd falls back to "ip route add".
+func routeDel(r Route) (err error) {
+	if nErr := netlinkRouteOp(unix.RTM_DELROUTE, 0, r); nErr == nil {
+		return nil
+	}
+
+	return shellRoute(shellRouteDelArgs(r))
+}
+
+// listRoutes dumps the kernel's IPv4 and IPv6 routing tables via
+// RTM_GETROUTE.
+func listRoutes() (routes []Route, err error) {
+	for _, family := range [...]int{unix.AF_INET, unix.AF_INET6} {
+		rs, lErr := dumpRoutes(family)
+		if lErr != nil {
+			return nil, lErr
+		}
+
+		routes = append(routes, rs...)
+	}
+
+	return routes, nil
+}
+
+// shellRoute runs an "ip route" command built by shellRouteAddArgs or
+// shellRouteDelArgs.
+func shellRoute(args []string) (err error) {
+	code, out, err := aghosRunCommand(args[0], args[1:]...)
+	if err != nil {
+		return fmt.Errorf("running %q: %w", args, err)
+	}
+
+	if code != 0 {
+		return fmt.Errorf("running %q: exit code %d: %s", args, code, out)
+	}
+
+	return nil
+}
+
+// shellRouteAddArgs builds the "ip route add <dst> via <gw> dev <iface>
+// [metric <metric>]" command line used as a fallback when the netlink path
+// fails.
+func shellRouteAddArgs(r Route) (args []string) {
+	args = []string{"ip", "route", "add", r.Dst.String(), "via", r.Gw.String(), "dev", r.Iface}
+	if r.Metric > 0 {
+		args = append(args, "metric", fmt.Sprintf("%d", r.Metric))
+	}
+
+	return args
+}
+
+// shellRouteDelArgs builds the "ip route del <dst> via <gw> dev <iface>"
+// command line used as a fallback when the netlink path fails.
+func shellRouteDelArgs(r Route) (args []string) {
+	return []string{"ip", "route", "del", r.Dst.String(), "via", r.Gw.String(), "dev", r.Iface}
+}
+
+// netlinkRouteOp sends a single RTM_NEWROUTE/RTM_DELROUTE request for r and
+// waits for the kernel's netlink ACK.
+func netlinkRouteOp(rtmType uint16, extraFlags uint16, r Route) (err error) {
+	ifi, err := net.InterfaceByName(r.Iface)
+	if err != nil {
+		return fmt.Errorf("looking up interface %s: %w", r.Iface, err)
+	}
+
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("opening netlink socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	msg := routeOpMessage(rtmType, extraFlags, r, ifi.Index)
+
+	if err = unix.Sendto(sock, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("sending netlink request: %w", err)
+	}
+
+	return readNetlinkAck(sock)
+}
+
+// readNetlinkAck reads netlink messages off sock until it sees the
+// NLMSG_ERROR ack for the request, returning nil if the ack's error code is
+// zero (success).
+func readNetlinkAck(sock int) (err error) {
+	buf := make([]byte, unix.Getpagesize())
+
+	n, _, err := unix.Recvfrom(sock, buf, 0)
+	if err != nil {
+		return fmt.Errorf("reading netlink ack: %w", err)
+	}
+
+	msgs, err := unix.ParseNetlinkMessage(buf[:n])
+	if err != nil {
+		return fmt.Errorf("parsing netlink ack: %w", err)
+	}
+
+	for _, m := range msgs {
+		if m.Header.Type != unix.NLMSG_ERROR || len(m.Data) < 4 {
+			continue
+		}
+
+		errno := int32(binary.LittleEndian.Uint32(m.Data[0:4]))
+		if errno != 0 {
+			return fmt.Errorf("netlink: %w", unix.Errno(-errno))
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("netlink: no ack received")
+}
+
+// rtaAlignTo is RTA_ALIGNTO: every rtattr is padded to a multiple of 4
+// bytes; see rtnetlink(7).
+const rtaAlignTo = 4
+
+// routeOpMessage builds the netlink request message for an RTM_NEWROUTE or
+// RTM_DELROUTE operation on r.
+func routeOpMessage(rtmType uint16, extraFlags uint16, r Route, oif int) (msg []byte) {
+	family := uint8(unix.AF_INET)
+	dstIP := r.Dst.IP.To4()
+	if dstIP == nil {
+		family = unix.AF_INET6
+		dstIP = r.Dst.IP.To16()
+	}
+
+	ones, _ := r.Dst.Mask.Size()
+
+	rtm := make([]byte, rtMsgLen)
+	rtm[0] = family
+	rtm[1] = byte(ones)
+	rtm[4] = unix.RT_TABLE_MAIN
+	rtm[5] = unix.RTPROT_STATIC
+	rtm[6] = unix.RT_SCOPE_UNIVERSE
+	rtm[7] = unix.RTN_UNICAST
+
+	body := append([]byte{}, rtm...)
+	body = append(body, encodeRtattr(unix.RTA_DST, dstIP)...)
+
+	gw := r.Gw.To4()
+	if family == unix.AF_INET6 {
+		gw = r.Gw.To16()
+	}
+
+	if gw != nil {
+		body = append(body, encodeRtattr(unix.RTA_GATEWAY, gw)...)
+	}
+
+	oifBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(oifBuf, uint32(oif))
+	body = append(body, encodeRtattr(unix.RTA_OIF, oifBuf)...)
+
+	if r.Metric > 0 {
+		prioBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(prioBuf, uint32(r.Metric))
+		body = append(body, encodeRtattr(unix.RTA_PRIORITY, prioBuf)...)
+	}
+
+	hdrLen := unix.SizeofNlMsghdr
+	total := hdrLen + len(body)
+	msg = make([]byte, total)
+
+	binary.LittleEndian.PutUint32(msg[0:4], uint32(total))
+	binary.LittleEndian.PutUint16(msg[4:6], rtmType)
+	binary.LittleEndian.PutUint16(msg[6:8], unix.NLM_F_REQUEST|unix.NLM_F_ACK|extraFlags)
+	copy(msg[hdrLen:], body)
+
+	return msg
+}
+
+// encodeRtattr encodes a single rtattr with the given type and value,
+// padded to rtaAlignTo.
+func encodeRtattr(attrType uint16, value []byte) (b []byte) {
+	l := 4 + len(value)
+	padded := (l + rtaAlignTo - 1) &^ (rtaAlignTo - 1)
+
+	b = make([]byte, padded)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(l))
+	binary.LittleEndian.PutUint16(b[2:4], attrType)
+	copy(b[4:], value)
+
+	return b
+}
+
+// dumpRoutes performs a single RTM_GETROUTE dump for family and decodes the
+// resulting routes.
+func dumpRoutes(family int) (routes []Route, err error) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("opening netlink socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	req := routeDumpRequest(family)
+	if err = unix.Sendto(sock, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("sending RTM_GETROUTE: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, _, rErr := unix.Recvfrom(sock, buf, 0)
+		if rErr != nil {
+			return nil, fmt.Errorf("reading netlink response: %w", rErr)
+		}
+
+		msgs, pErr := unix.ParseNetlinkMessage(buf[:n])
+		if pErr != nil {
+			return nil, fmt.Errorf("parsing netlink messages: %w", pErr)
+		}
+
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case unix.NLMSG_DONE:
+				return routes, nil
+			case unix.RTM_NEWROUTE:
+				if r, ok := routeFromMessage(m); ok {
+					routes = append(routes, r)
+				}
+			}
+		}
+	}
+}
+
+// routeFromMessage decodes a single RTM_NEWROUTE dump entry into a Route.
+func routeFromMessage(m unix.NetlinkMessage) (r Route, ok bool) {
+	if len(m.Data) < rtMsgLen {
+		return Route{}, false
+	}
+
+	family := m.Data[0]
+	dstLen := int(m.Data[1])
+
+	attrs, err := unix.ParseNetlinkRouteAttr(&m)
+	if err != nil {
+		return Route{}, false
+	}
+
+	var dstIP, gwIP net.IP
+	var oif int
+	var metric int
+
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case unix.RTA_DST:
+			dstIP = net.IP(append([]byte(nil), a.Value...))
+		case unix.RTA_GATEWAY:
+			gwIP = net.IP(append([]byte(nil), a.Value...))
+		case unix.RTA_OIF:
+			oif = int(binary.LittleEndian.Uint32(a.Value))
+		case unix.RTA_PRIORITY:
+			metric = int(binary.LittleEndian.Uint32(a.Value))
+		}
+	}
+
+	if dstIP == nil {
+		if family == unix.AF_INET {
+			dstIP = net.IPv4zero
+		} else {
+			dstIP = net.IPv6zero
+		}
+	}
+
+	bits := 32
+	if family == unix.AF_INET6 {
+		bits = 128
+	}
+
+	iface := ""
+	if ifi, iErr := net.InterfaceByIndex(oif); iErr == nil {
+		iface = ifi.Name
+	}
+
+	return Route{
+		Dst:    &net.IPNet{IP: dstIP, Mask: net.CIDRMask(dstLen, bits)},
+		Gw:     gwIP,
+		Iface:  iface,
+		Metric: metric,
+	}, true
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellRouteArgsLinux(t *testing.T) {
+	_, dst, err := net.ParseCIDR("10.0.0.0/32")
+	assert.NoError(t, err)
+
+	r := Route{
+		Dst:   dst,
+		Gw:    net.ParseIP("166.111.8.1"),
+		Iface: "eth0",
+	}
+
+	assert.Equal(t, []string{
+		"ip", "route", "add", "10.0.0.0/32", "via", "166.111.8.1", "dev", "eth0",
+	}, shellRouteAddArgs(r))
+
+	assert.Equal(t, []string{
+		"ip", "route", "del", "10.0.0.0/32", "via", "166.111.8.1", "dev", "eth0",
+	}, shellRouteDelArgs(r))
+
+	r.Metric = 100
+	assert.Equal(t, []string{
+		"ip", "route", "add", "10.0.0.0/32", "via", "166.111.8.1", "dev", "eth0",
+		"metric", "100",
+	}, shellRouteAddArgs(r))
+}
+
+func TestRouteTable_shellFallback(t *testing.T) {
+	_, dst, err := net.ParseCIDR("10.0.0.0/32")
+	assert.NoError(t, err)
+
+	gw := net.ParseIP("166.111.8.1")
+
+	// The test process won't have CAP_NET_ADMIN, so the netlink attempt in
+	// routeAdd/routeDel always fails and falls back to the shell commands
+	// stubbed out here, the same way TestGatewayIP used to stub "ip route
+	// show".
+	testShell{
+		"ip route add 10.0.0.0/32 via 166.111.8.1 dev eth0": {code: 0},
+		"ip route del 10.0.0.0/32 via 166.111.8.1 dev eth0": {code: 0},
+	}.set(t)
+
+	rt := NewRouteTable()
+
+	t.Run("add_twice_then_del_twice", func(t *testing.T) {
+		assert.NoError(t, rt.Add(dst, gw, "eth0", 0))
+		assert.NoError(t, rt.Add(dst, gw, "eth0", 0))
+
+		// The second Add only bumped the refcount, so the first Del must
+		// not remove the tracked route yet.
+		assert.NoError(t, rt.Del(dst, gw, "eth0"))
+		assert.Contains(t, rt.refs, routeKey(dst, gw, "eth0"))
+
+		assert.NoError(t, rt.Del(dst, gw, "eth0"))
+		assert.NotContains(t, rt.refs, routeKey(dst, gw, "eth0"))
+	})
+
+	t.Run("del_untracked", func(t *testing.T) {
+		assert.Error(t, rt.Del(dst, gw, "eth0"))
+	})
+}
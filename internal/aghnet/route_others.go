@@ -0,0 +1,18 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package aghnet
+
+// routeAdd is unimplemented on this platform.
+func routeAdd(r Route) (err error) {
+	return errNotImplemented
+}
+
+// routeDel is unimplemented on this platform.
+func routeDel(r Route) (err error) {
+	return errNotImplemented
+}
+
+// listRoutes is unimplemented on this platform.
+func listRoutes() (routes []Route, err error) {
+	return nil, errNotImplemented
+}
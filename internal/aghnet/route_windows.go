@@ -0,0 +1,104 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// routeAdd installs r by shelling out to "route ADD" for IPv4 destinations
+// or "netsh interface ipv6 add route" for IPv6 ones, since the "route"
+// command doesn't support IPv6.  A native backend via CreateIpForwardEntry2,
+// mirroring the read path in gateway_windows_iphlpapi.go, is left as a
+// follow-up: it needs a populated MIB_IPFORWARD_ROW2 (interface LUID,
+// prefix, next hop) built from scratch for an arbitrary destination, rather
+// than just reading one back.
+func routeAdd(r Route) (err error) {
+	return shellRouteWindows(shellRouteAddArgsWindows(r))
+}
+
+// routeDel removes r the same way routeAdd installs it.
+func routeDel(r Route) (err error) {
+	return shellRouteWindows(shellRouteDelArgsWindows(r))
+}
+
+// listRoutes is not implemented on Windows yet; see the routeAdd doc
+// comment for why a native backend isn't in place.
+func listRoutes() (routes []Route, err error) {
+	return nil, errNotImplemented
+}
+
+// shellRouteWindows runs a "route" or "netsh" command built by
+// shellRouteAddArgsWindows or shellRouteDelArgsWindows.
+func shellRouteWindows(args []string) (err error) {
+	code, out, err := aghosRunCommand(args[0], args[1:]...)
+	if err != nil {
+		return fmt.Errorf("running %q: %w", args, err)
+	}
+
+	if code != 0 {
+		return fmt.Errorf("running %q: exit code %d: %s", args, code, out)
+	}
+
+	return nil
+}
+
+// shellRouteAddArgsWindows builds the command line that installs r: "route
+// ADD <dst> MASK <mask> <gw> [METRIC <metric>]" for IPv4, or "netsh
+// interface ipv6 add route <dst> <iface> <gw> [metric=<metric>]" for IPv6,
+// since "route ADD" rejects IPv6 destinations.
+func shellRouteAddArgsWindows(r Route) (args []string) {
+	if r.Dst.IP.To4() == nil {
+		return netshRouteAddArgsWindows(r)
+	}
+
+	ones, bits := r.Dst.Mask.Size()
+	mask := net.IP(net.CIDRMask(ones, bits)).String()
+
+	args = []string{"route", "ADD", r.Dst.IP.String(), "MASK", mask, r.Gw.String()}
+	if r.Metric > 0 {
+		args = append(args, "METRIC", strconv.Itoa(r.Metric))
+	}
+
+	return args
+}
+
+// shellRouteDelArgsWindows builds the command line that removes r: "route
+// DELETE <dst>" for IPv4, or "netsh interface ipv6 delete route <dst>
+// <iface>" for IPv6.
+func shellRouteDelArgsWindows(r Route) (args []string) {
+	if r.Dst.IP.To4() == nil {
+		return netshRouteDelArgsWindows(r)
+	}
+
+	return []string{"route", "DELETE", r.Dst.IP.String()}
+}
+
+// netshRouteAddArgsWindows builds the "netsh interface ipv6 add route
+// <prefix> <iface> <gw> [metric=<metric>]" command line used for IPv6
+// destinations.
+func netshRouteAddArgsWindows(r Route) (args []string) {
+	ones, _ := r.Dst.Mask.Size()
+	prefix := fmt.Sprintf("%s/%d", r.Dst.IP, ones)
+
+	args = []string{
+		"netsh", "interface", "ipv6", "add", "route",
+		prefix, r.Iface, r.Gw.String(),
+	}
+	if r.Metric > 0 {
+		args = append(args, "metric="+strconv.Itoa(r.Metric))
+	}
+
+	return args
+}
+
+// netshRouteDelArgsWindows builds the "netsh interface ipv6 delete route
+// <prefix> <iface>" command line used for IPv6 destinations.
+func netshRouteDelArgsWindows(r Route) (args []string) {
+	ones, _ := r.Dst.Mask.Size()
+	prefix := fmt.Sprintf("%s/%d", r.Dst.IP, ones)
+
+	return []string{"netsh", "interface", "ipv6", "delete", "route", prefix, r.Iface}
+}
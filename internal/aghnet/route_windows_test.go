@@ -0,0 +1,56 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellRouteArgsWindows(t *testing.T) {
+	r := Route{
+		Dst:   &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Gw:    net.ParseIP("166.111.8.1"),
+		Iface: "Ethernet",
+	}
+
+	assert.Equal(t, []string{
+		"route", "ADD", "0.0.0.0", "MASK", "0.0.0.0", "166.111.8.1",
+	}, shellRouteAddArgsWindows(r))
+
+	assert.Equal(t, []string{
+		"route", "DELETE", "0.0.0.0",
+	}, shellRouteDelArgsWindows(r))
+
+	rWithMetric := r
+	rWithMetric.Metric = 10
+
+	assert.Equal(t, []string{
+		"route", "ADD", "0.0.0.0", "MASK", "0.0.0.0", "166.111.8.1", "METRIC", "10",
+	}, shellRouteAddArgsWindows(rWithMetric))
+
+	r6 := Route{
+		Dst:   &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(64, 128)},
+		Gw:    net.ParseIP("2001:db8::1"),
+		Iface: "Ethernet",
+	}
+
+	assert.Equal(t, []string{
+		"netsh", "interface", "ipv6", "add", "route",
+		"2001:db8::/64", "Ethernet", "2001:db8::1",
+	}, shellRouteAddArgsWindows(r6))
+
+	assert.Equal(t, []string{
+		"netsh", "interface", "ipv6", "delete", "route", "2001:db8::/64", "Ethernet",
+	}, shellRouteDelArgsWindows(r6))
+
+	r6WithMetric := r6
+	r6WithMetric.Metric = 5
+
+	assert.Equal(t, []string{
+		"netsh", "interface", "ipv6", "add", "route",
+		"2001:db8::/64", "Ethernet", "2001:db8::1", "metric=5",
+	}, shellRouteAddArgsWindows(r6WithMetric))
+}
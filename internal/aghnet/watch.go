@@ -0,0 +1,137 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// EventKind is the kind of change an Event reports.
+type EventKind int
+
+// Event kinds emitted by a Watcher.
+const (
+	EventLinkAdd EventKind = iota
+	EventLinkDel
+	EventAddrAdd
+	EventAddrDel
+	EventRouteAdd
+	EventRouteDel
+)
+
+// String implements the fmt.Stringer interface for EventKind.
+func (k EventKind) String() (s string) {
+	switch k {
+	case EventLinkAdd:
+		return "link_add"
+	case EventLinkDel:
+		return "link_del"
+	case EventAddrAdd:
+		return "addr_add"
+	case EventAddrDel:
+		return "addr_del"
+	case EventRouteAdd:
+		return "route_add"
+	case EventRouteDel:
+		return "route_del"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is a single interface, address, or route change reported by a
+// Watcher.  Addr is only set for EventAddrAdd, EventAddrDel, and the route
+// events, where it carries the route's gateway.
+type Event struct {
+	Kind  EventKind
+	Iface string
+	Addr  net.IP
+}
+
+// watcherBackend is the platform-specific half of a Watcher: it reads
+// interface/address change notifications from the OS and forwards them as
+// Events until stop is closed, then returns.
+type watcherBackend interface {
+	// run reads notifications and sends the corresponding Events to
+	// events until stop is closed, then returns nil.  It returns a
+	// non-nil error only if reading notifications fails for a reason
+	// other than the backend being closed.
+	run(events chan<- Event, stop <-chan struct{}) (err error)
+
+	// close releases the backend's OS resources, unblocking any running
+	// call to run.
+	close() (err error)
+}
+
+// newWatcherBackend constructs the backend used by NewWatcher.  It's a
+// variable so that tests can substitute a fake backend, the same way
+// aghosRunCommand and addrCandidatesFunc are substituted.
+var newWatcherBackend = newOSWatcherBackend
+
+// Watcher emits Events whenever interfaces appear or disappear, or their
+// addresses change, so that callers like the DHCP server and the DNS
+// listener sockets can react without polling CollectAllIfacesAddrs on a
+// timer.
+//
+// A zero Watcher is not valid; use NewWatcher.
+type Watcher struct {
+	backend watcherBackend
+	events  chan Event
+	stop    chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewWatcher creates and starts a new *Watcher.  Call Close once the
+// Watcher is no longer needed.
+func NewWatcher() (w *Watcher, err error) {
+	backend, err := newWatcherBackend()
+	if err != nil {
+		return nil, fmt.Errorf("aghnet: starting watcher: %w", err)
+	}
+
+	w = &Watcher{
+		backend: backend,
+		events:  make(chan Event, 16),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// run reads Events from w.backend until it's closed, forwarding them to
+// w.events.
+func (w *Watcher) run() {
+	defer close(w.done)
+	defer close(w.events)
+
+	if err := w.backend.run(w.events, w.stop); err != nil {
+		log.Error("aghnet: watcher: %s", err)
+	}
+}
+
+// Subscribe returns the channel on which w emits Events.  The channel is
+// closed once w is closed and has stopped.
+func (w *Watcher) Subscribe() (events <-chan Event) {
+	return w.events
+}
+
+// Close stops w, releases its backend's resources, and waits for the
+// watcher's goroutine to exit.  Close is safe for concurrent use and always
+// returns the same error.
+func (w *Watcher) Close() (err error) {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		w.closeErr = w.backend.close()
+		<-w.done
+	})
+
+	return w.closeErr
+}
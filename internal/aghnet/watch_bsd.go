@@ -0,0 +1,121 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package aghnet
+
+import (
+	"encoding/binary"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// bsdWatcherBackend is the Darwin/BSD watcherBackend: a PF_ROUTE socket,
+// which receives RTM_IFINFO/RTM_NEWADDR/RTM_DELADDR messages for every
+// interface and address change on the host (PF_ROUTE sockets can't be
+// scoped to particular message types the way NETLINK_ROUTE multicast
+// groups can).
+type bsdWatcherBackend struct {
+	sock int
+}
+
+// newOSWatcherBackend opens a PF_ROUTE/SOCK_RAW socket.
+func newOSWatcherBackend() (b watcherBackend, err error) {
+	sock, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bsdWatcherBackend{sock: sock}, nil
+}
+
+// run implements the watcherBackend interface for *bsdWatcherBackend.
+func (b *bsdWatcherBackend) run(events chan<- Event, stop <-chan struct{}) (err error) {
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, rErr := unix.Read(b.sock, buf)
+		if rErr != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return rErr
+			}
+		}
+
+		ev, ok := bsdEventFromMessage(buf[:n])
+		if !ok {
+			continue
+		}
+
+		select {
+		case events <- ev:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// close implements the watcherBackend interface for *bsdWatcherBackend.
+func (b *bsdWatcherBackend) close() (err error) {
+	return unix.Close(b.sock)
+}
+
+// rtmIfMsgLen is the size, in bytes, of the fixed part of a struct
+// if_msghdr (and of struct ifa_msghdr, which shares the same layout up to
+// and including ifam_index); see route(4):
+//
+//	u_short ifm_msglen;  // 0
+//	u_char  ifm_version; // 2
+//	u_char  ifm_type;    // 3
+//	int     ifm_addrs;   // 4
+//	int     ifm_flags;   // 8
+//	u_short ifm_index;   // 12
+const rtmIfMsgLen = 16
+
+// ifIndexOffset and ifFlagsOffset are the byte offsets of ifm_index and
+// ifm_flags within a struct if_msghdr/ifa_msghdr.
+const (
+	ifFlagsOffset = 8
+	ifIndexOffset = 12
+)
+
+// bsdEventFromMessage decodes a single PF_ROUTE message into an Event, if
+// it's one of the message types Watcher cares about.
+//
+// The address carried by RTM_NEWADDR/RTM_DELADDR messages isn't decoded:
+// doing so requires walking the ifa_msghdr address bitmap the same way
+// gatewayFromRouteReply does for rt_msghdr, using struct offsets that
+// differ across Darwin, FreeBSD, NetBSD, OpenBSD, and DragonFly.  Event.Addr
+// is left nil here; callers that need the new address can re-read it via
+// CollectAllIfacesAddrs once notified.
+func bsdEventFromMessage(b []byte) (ev Event, ok bool) {
+	if len(b) < rtmIfMsgLen {
+		return Event{}, false
+	}
+
+	msgType := b[3]
+	index := int(binary.LittleEndian.Uint16(b[ifIndexOffset : ifIndexOffset+2]))
+
+	var kind EventKind
+	switch msgType {
+	case unix.RTM_IFINFO:
+		flags := binary.LittleEndian.Uint32(b[ifFlagsOffset : ifFlagsOffset+4])
+		kind = EventLinkAdd
+		if flags&unix.IFF_UP == 0 {
+			kind = EventLinkDel
+		}
+	case unix.RTM_NEWADDR:
+		kind = EventAddrAdd
+	case unix.RTM_DELADDR:
+		kind = EventAddrDel
+	default:
+		return Event{}, false
+	}
+
+	name := ""
+	if ifi, err := net.InterfaceByIndex(index); err == nil {
+		name = ifi.Name
+	}
+
+	return Event{Kind: kind, Iface: name}, true
+}
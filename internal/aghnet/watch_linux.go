@@ -0,0 +1,210 @@
+//go:build linux
+
+package aghnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// linuxWatcherBackend is the Linux watcherBackend: an AF_NETLINK socket
+// subscribed to link and address change multicast groups.
+type linuxWatcherBackend struct {
+	sock int
+}
+
+// newOSWatcherBackend opens a NETLINK_ROUTE socket and subscribes it to the
+// RTMGRP_LINK, RTMGRP_IPV4_IFADDR, RTMGRP_IPV6_IFADDR, RTMGRP_IPV4_ROUTE,
+// and RTMGRP_IPV6_ROUTE multicast groups.
+func newOSWatcherBackend() (b watcherBackend, err error) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := uint32(unix.RTMGRP_LINK |
+		unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR |
+		unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE)
+
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups}
+	if err = unix.Bind(sock, addr); err != nil {
+		_ = unix.Close(sock)
+
+		return nil, err
+	}
+
+	return &linuxWatcherBackend{sock: sock}, nil
+}
+
+// run implements the watcherBackend interface for *linuxWatcherBackend.
+func (b *linuxWatcherBackend) run(events chan<- Event, stop <-chan struct{}) (err error) {
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, _, rErr := unix.Recvfrom(b.sock, buf, 0)
+		if rErr != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return rErr
+			}
+		}
+
+		msgs, pErr := unix.ParseNetlinkMessage(buf[:n])
+		if pErr != nil {
+			continue
+		}
+
+		for _, m := range msgs {
+			ev, ok := linuxEventFromMessage(m)
+			if !ok {
+				continue
+			}
+
+			select {
+			case events <- ev:
+			case <-stop:
+				return nil
+			}
+		}
+	}
+}
+
+// close implements the watcherBackend interface for *linuxWatcherBackend.
+func (b *linuxWatcherBackend) close() (err error) {
+	return unix.Close(b.sock)
+}
+
+// ifInfoMsgLen is the size, in bytes, of the fixed part of a struct
+// ifinfomsg; see rtnetlink(7).
+const ifInfoMsgLen = 16
+
+// ifAddrMsgLen is the size, in bytes, of the fixed part of a struct
+// ifaddrmsg; see rtnetlink(7).
+const ifAddrMsgLen = 8
+
+// linuxEventFromMessage decodes a single netlink message into an Event, if
+// it's one of the message types Watcher cares about.
+func linuxEventFromMessage(m unix.NetlinkMessage) (ev Event, ok bool) {
+	switch m.Header.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		return linuxLinkEvent(m)
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		return linuxAddrEvent(m)
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		return linuxRouteEvent(m)
+	default:
+		return Event{}, false
+	}
+}
+
+// linuxLinkEvent decodes an RTM_NEWLINK/RTM_DELLINK message.
+func linuxLinkEvent(m unix.NetlinkMessage) (ev Event, ok bool) {
+	if len(m.Data) < ifInfoMsgLen {
+		return Event{}, false
+	}
+
+	index := int(binary.LittleEndian.Uint32(m.Data[4:8]))
+
+	kind := EventLinkAdd
+	if m.Header.Type == unix.RTM_DELLINK {
+		kind = EventLinkDel
+	}
+
+	return Event{Kind: kind, Iface: linuxIfaceName(m, index)}, true
+}
+
+// linuxAddrEvent decodes an RTM_NEWADDR/RTM_DELADDR message.
+func linuxAddrEvent(m unix.NetlinkMessage) (ev Event, ok bool) {
+	if len(m.Data) < ifAddrMsgLen {
+		return Event{}, false
+	}
+
+	index := int(binary.LittleEndian.Uint32(m.Data[4:8]))
+
+	var addr net.IP
+	if attrs, aErr := unix.ParseNetlinkRouteAttr(&m); aErr == nil {
+		for _, a := range attrs {
+			if a.Attr.Type == unix.IFA_ADDRESS || a.Attr.Type == unix.IFA_LOCAL {
+				addr = net.IP(append([]byte(nil), a.Value...))
+			}
+		}
+	}
+
+	kind := EventAddrAdd
+	if m.Header.Type == unix.RTM_DELADDR {
+		kind = EventAddrDel
+	}
+
+	return Event{Kind: kind, Iface: linuxIfaceName(m, index), Addr: addr}, true
+}
+
+// linuxRouteEvent decodes an RTM_NEWROUTE/RTM_DELROUTE message reporting a
+// change to the default route (dst_len == 0), so that a default-gateway
+// change wakes up subscribers the same way a link or address change does.
+// Non-default routes are ignored, the same way gatewayFromRouteMessage in
+// gateway_linux.go ignores them, since those change far more often and
+// without bearing on which gateway traffic leaves through.  Event.Iface is
+// the route's outgoing interface (RTA_OIF) and Event.Addr is its gateway
+// (RTA_GATEWAY), if any.
+func linuxRouteEvent(m unix.NetlinkMessage) (ev Event, ok bool) {
+	if len(m.Data) < rtMsgLen {
+		return Event{}, false
+	}
+
+	// The second byte of struct rtmsg is rtm_dst_len; see rtnetlink(7).
+	if m.Data[1] != 0 {
+		return Event{}, false
+	}
+
+	var oif int
+	var gw net.IP
+
+	attrs, err := unix.ParseNetlinkRouteAttr(&m)
+	if err != nil {
+		return Event{}, false
+	}
+
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case unix.RTA_OIF:
+			oif = int(binary.LittleEndian.Uint32(a.Value))
+		case unix.RTA_GATEWAY:
+			gw = net.IP(append([]byte(nil), a.Value...))
+		}
+	}
+
+	name := ""
+	if ifi, iErr := net.InterfaceByIndex(oif); iErr == nil {
+		name = ifi.Name
+	}
+
+	kind := EventRouteAdd
+	if m.Header.Type == unix.RTM_DELROUTE {
+		kind = EventRouteDel
+	}
+
+	return Event{Kind: kind, Iface: name, Addr: gw}, true
+}
+
+// linuxIfaceName resolves the name of the interface the message m concerns,
+// preferring the IFLA_IFNAME attribute (only present on link messages) and
+// falling back to looking the index up in the current interface list.
+func linuxIfaceName(m unix.NetlinkMessage, index int) (name string) {
+	if attrs, err := unix.ParseNetlinkRouteAttr(&m); err == nil {
+		for _, a := range attrs {
+			if a.Attr.Type == unix.IFLA_IFNAME {
+				return string(bytes.TrimRight(a.Value, "\x00"))
+			}
+		}
+	}
+
+	if ifi, err := net.InterfaceByIndex(index); err == nil {
+		return ifi.Name
+	}
+
+	return ""
+}
@@ -0,0 +1,25 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package aghnet
+
+// noopWatcherBackend is used on platforms without a native notification
+// mechanism; run simply blocks until stop is closed, so Watcher never
+// emits any Event.
+type noopWatcherBackend struct{}
+
+// newOSWatcherBackend returns a backend that never emits any Event.
+func newOSWatcherBackend() (b watcherBackend, err error) {
+	return noopWatcherBackend{}, nil
+}
+
+// run implements the watcherBackend interface for noopWatcherBackend.
+func (noopWatcherBackend) run(_ chan<- Event, stop <-chan struct{}) (err error) {
+	<-stop
+
+	return nil
+}
+
+// close implements the watcherBackend interface for noopWatcherBackend.
+func (noopWatcherBackend) close() (err error) {
+	return nil
+}
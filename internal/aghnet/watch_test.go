@@ -0,0 +1,113 @@
+package aghnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWatcherBackend is a watcherBackend that replays a fixed slice of
+// Events and then blocks until stopped, used to exercise Watcher itself
+// without touching any real OS notification mechanism.  It's the Watcher
+// analogue of testShell for aghosRunCommand and of addrCandidatesFunc's
+// test substitute for SelectSourceAddr.
+type fakeWatcherBackend struct {
+	events []Event
+	closed chan struct{}
+}
+
+func newFakeWatcherBackend(events ...Event) *fakeWatcherBackend {
+	return &fakeWatcherBackend{
+		events: events,
+		closed: make(chan struct{}),
+	}
+}
+
+func (b *fakeWatcherBackend) run(events chan<- Event, stop <-chan struct{}) (err error) {
+	for _, ev := range b.events {
+		select {
+		case events <- ev:
+		case <-stop:
+			return nil
+		}
+	}
+
+	select {
+	case <-stop:
+		return nil
+	case <-b.closed:
+		return nil
+	}
+}
+
+func (b *fakeWatcherBackend) close() (err error) {
+	close(b.closed)
+
+	return nil
+}
+
+// setTestWatcherBackend substitutes newWatcherBackend with one that always
+// returns backend, restoring the previous value once the test finishes.
+func setTestWatcherBackend(t testing.TB, backend watcherBackend) {
+	t.Helper()
+
+	prev := newWatcherBackend
+	t.Cleanup(func() { newWatcherBackend = prev })
+	newWatcherBackend = func() (watcherBackend, error) {
+		return backend, nil
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	want := []Event{
+		{Kind: EventLinkAdd, Iface: "eth0"},
+		{Kind: EventAddrAdd, Iface: "eth0", Addr: []byte{192, 168, 1, 1}},
+		{Kind: EventAddrDel, Iface: "eth0", Addr: []byte{192, 168, 1, 1}},
+		{Kind: EventLinkDel, Iface: "eth0"},
+	}
+
+	setTestWatcherBackend(t, newFakeWatcherBackend(want...))
+
+	w, err := NewWatcher()
+	require.NoError(t, err)
+
+	var got []Event
+	for i := 0; i < len(want); i++ {
+		select {
+		case ev := <-w.Subscribe():
+			got = append(got, ev)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	assert.Equal(t, want, got)
+	assert.NoError(t, w.Close())
+
+	// Close must be idempotent.
+	assert.NoError(t, w.Close())
+
+	_, ok := <-w.Subscribe()
+	assert.False(t, ok)
+}
+
+func TestEventKind_String(t *testing.T) {
+	testCases := []struct {
+		kind EventKind
+		want string
+	}{
+		{EventLinkAdd, "link_add"},
+		{EventLinkDel, "link_del"},
+		{EventAddrAdd, "addr_add"},
+		{EventAddrDel, "addr_del"},
+		{EventRouteAdd, "route_add"},
+		{EventRouteDel, "route_del"},
+		{EventKind(99), "EventKind(99)"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, tc.kind.String())
+	}
+}
@@ -0,0 +1,195 @@
+//go:build windows
+
+package aghnet
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modIphlpapiWatch               = windows.NewLazySystemDLL("iphlpapi.dll")
+	procNotifyIpInterfaceChange    = modIphlpapiWatch.NewProc("NotifyIpInterfaceChange")
+	procNotifyUnicastAddressChange = modIphlpapiWatch.NewProc("NotifyUnicastIpAddressChange")
+	procCancelMibChangeNotify2     = modIphlpapiWatch.NewProc("CancelMibChangeNotify2")
+)
+
+// MIB_NOTIFICATION_TYPE values, shared by the interface- and
+// address-change callbacks.
+const (
+	mibParameterNotification = 0
+	mibAddInstance           = 1
+	mibDeleteInstance        = 2
+	mibInitialNotification   = 3
+)
+
+// windowsWatcherBackend is the Windows watcherBackend: it registers
+// NotifyIpInterfaceChange and NotifyUnicastIpAddressChange callbacks and
+// forwards what they report as Events.
+type windowsWatcherBackend struct {
+	ifaceHandle uintptr
+	addrHandle  uintptr
+
+	mu     sync.Mutex
+	events chan<- Event
+	stop   <-chan struct{}
+}
+
+// newOSWatcherBackend registers the interface- and address-change
+// callbacks used to implement the Windows watcherBackend.
+func newOSWatcherBackend() (b watcherBackend, err error) {
+	wb := &windowsWatcherBackend{}
+
+	ifaceCB := windows.NewCallback(wb.onInterfaceChange)
+
+	var ifaceHandle uintptr
+	ret, _, _ := procNotifyIpInterfaceChange.Call(
+		uintptr(windows.AF_UNSPEC),
+		ifaceCB,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&ifaceHandle)),
+	)
+	if ret != 0 {
+		return nil, windowsErrno(ret)
+	}
+
+	addrCB := windows.NewCallback(wb.onAddressChange)
+
+	var addrHandle uintptr
+	ret, _, _ = procNotifyUnicastAddressChange.Call(
+		uintptr(windows.AF_UNSPEC),
+		addrCB,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&addrHandle)),
+	)
+	if ret != 0 {
+		_, _, _ = procCancelMibChangeNotify2.Call(ifaceHandle)
+
+		return nil, windowsErrno(ret)
+	}
+
+	wb.ifaceHandle = ifaceHandle
+	wb.addrHandle = addrHandle
+
+	return wb, nil
+}
+
+// run implements the watcherBackend interface for *windowsWatcherBackend.
+// The actual notifications arrive on OS-managed threads via the callbacks
+// registered in newOSWatcherBackend, so run only needs to hand off the
+// event channel and block until stop is closed.
+func (b *windowsWatcherBackend) run(events chan<- Event, stop <-chan struct{}) (err error) {
+	b.mu.Lock()
+	b.events = events
+	b.stop = stop
+	b.mu.Unlock()
+
+	<-stop
+
+	return nil
+}
+
+// close implements the watcherBackend interface for *windowsWatcherBackend.
+func (b *windowsWatcherBackend) close() (err error) {
+	_, _, _ = procCancelMibChangeNotify2.Call(b.ifaceHandle)
+	_, _, _ = procCancelMibChangeNotify2.Call(b.addrHandle)
+
+	return nil
+}
+
+// send delivers ev on b.events, if run has started and stop hasn't fired
+// yet.
+func (b *windowsWatcherBackend) send(ev Event) {
+	b.mu.Lock()
+	events, stop := b.events, b.stop
+	b.mu.Unlock()
+
+	if events == nil {
+		return
+	}
+
+	select {
+	case events <- ev:
+	case <-stop:
+	}
+}
+
+// onInterfaceChange is the callback passed to NotifyIpInterfaceChange; its
+// signature matches PIPINTERFACE_CHANGE_CALLBACK.
+func (b *windowsWatcherBackend) onInterfaceChange(
+	_ uintptr,
+	row *mibIPInterfaceRow,
+	notificationType uint32,
+) uintptr {
+	kind := EventLinkAdd
+	if notificationType == mibDeleteInstance {
+		kind = EventLinkDel
+	}
+
+	name := ""
+	if row != nil {
+		if ifi, err := net.InterfaceByIndex(int(row.interfaceIndex)); err == nil {
+			name = ifi.Name
+		}
+	}
+
+	b.send(Event{Kind: kind, Iface: name})
+
+	return 0
+}
+
+// onAddressChange is the callback passed to NotifyUnicastIpAddressChange;
+// its signature matches PUNICAST_IPADDRESS_CHANGE_CALLBACK.
+func (b *windowsWatcherBackend) onAddressChange(
+	_ uintptr,
+	row *mibUnicastIPAddressRow,
+	notificationType uint32,
+) uintptr {
+	kind := EventAddrAdd
+	if notificationType == mibDeleteInstance {
+		kind = EventAddrDel
+	}
+
+	var addr net.IP
+	name := ""
+	if row != nil {
+		addr = sockaddrInetIP(row.address)
+		if ifi, err := net.InterfaceByIndex(int(row.interfaceIndex)); err == nil {
+			name = ifi.Name
+		}
+	}
+
+	b.send(Event{Kind: kind, Iface: name, Addr: addr})
+
+	return 0
+}
+
+// mibIPInterfaceRow mirrors enough of MIB_IPINTERFACE_ROW for the
+// interface-change callback; trailing fields are left as raw padding.
+type mibIPInterfaceRow struct {
+	family         uint16
+	interfaceLuid  uint64
+	interfaceIndex uint32
+	_              [184]byte
+}
+
+// mibUnicastIPAddressRow mirrors enough of MIB_UNICASTIPADDRESS_ROW for the
+// address-change callback; trailing fields are left as raw padding.
+type mibUnicastIPAddressRow struct {
+	address        sockaddrInet
+	interfaceLuid  uint64
+	interfaceIndex uint32
+	_              [40]byte
+}
+
+// windowsErrno converts a raw Win32 error code, as returned by the iphlpapi
+// functions called here, into an error.
+func windowsErrno(code uintptr) (err error) {
+	return syscall.Errno(code)
+}